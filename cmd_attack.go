@@ -0,0 +1,266 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// cmdAttack runs a sine/compound-wave load test against a set of targets
+// read from /dev/stdin, writing recorded results to stdout so they can be
+// piped straight into "sine report"/"sine plot"/"sine encode", or saved
+// to a file for later analysis.
+func cmdAttack(args []string) {
+	fs := flag.NewFlagSet("attack", flag.ExitOnError)
+
+	opts := paceOpts{}
+	fs.DurationVar(&opts.period, "period", 10*time.Minute, "Period of the sine wave")
+	fs.IntVar(&opts.mean, "mean", 2, "The Mean req/1s of the sine wave")
+	fs.IntVar(&opts.amplitude, "amplitude", 1, "The Amplitude in req/1s of the sine wave")
+	fs.Float64Var(&opts.startAt, "startAt", 0, "The phase at which to start the sine wave, in radians")
+	fs.DurationVar(&opts.duration, "duration", 0, "Duration of the test, e.g. 30s or 1h; 0 runs until stopped")
+	fs.DurationVar(&opts.timeout, "timeout", vegeta.DefaultTimeout, "Requests timeout")
+	fs.BoolVar(&opts.keepalive, "keepalive", true, "Use persistent connections")
+	format := fs.String("format", "json", "Targets format, either \"http\", \"json\" or \"auto\" to sniff /dev/stdin")
+	bodyFile := fs.String("body", "", "File whose contents form the default request body for every target")
+	varsFile := fs.String("vars", "", "JSON file of variables to expand into target URLs/headers/bodies")
+	var vars varsFlag
+	fs.Var(&vars, "var", "key=value variable to expand into target URLs/headers/bodies, may be repeated")
+	storageDir := fs.String("storage-dir", "", "Directory to persist results to as a queryable time-series; required by -http-listen")
+	httpListen := fs.String("http-listen", "", "Address to serve /metrics/range and /metrics/live on, e.g. :8080")
+	var waves wavesFlag
+	fs.Var(&waves, "wave", "Component of a compound pacer, e.g. sine:period=10m,mean=100,amp=30,phase=0 or ramp:start=10,end=200,duration=1h or square:period=5m,low=50,high=150,duty=0.3; may be repeated to sum components, overrides -period/-mean/-amplitude/-startAt")
+	role := fs.String("role", "", "Distributed mode role, either \"leader\" or \"follower\"; empty runs standalone")
+	peers := fs.String("peers", "", "Comma-separated follower host:port addresses; leader-only")
+	listen := fs.String("listen", "", "Address to accept the leader's control connection on; follower-only")
+	checkpointFile := fs.String("checkpoint", "", "File to periodically save progress to, for -resume")
+	resume := fs.Bool("resume", false, "Resume from -checkpoint instead of starting the rate curve and hit count from zero")
+	reloadConfigFile := fs.String("reload-config", "", "File of {period,mean,amplitude,startAt} to reload the pacer from on SIGUSR2")
+	usr1Report := fs.String("usr1-report", "vegeta-sine-report.txt", "File to dump an intermediate text report to on SIGUSR1, without stopping the attack")
+	fs.Parse(args)
+
+	if *httpListen != "" && *storageDir == "" {
+		*storageDir = filepath.Join(os.TempDir(), fmt.Sprintf("vegeta-sine-%d", os.Getpid()))
+	}
+
+	fmt.Fprintf(os.Stderr, "Options: %#v\n", opts)
+
+	// These values are well-described at
+	// https://github.com/tsenart/vegeta/blob/d73edf2bc2663d83848da2a97a8401a7ed1440bc/lib/pacer.go#L101-L132
+	pacer, err := buildPacer(opts, waves)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "Using pacer: %v\n", pacer)
+
+	var duration_text string
+	if opts.duration == 0 {
+		duration_text = "infinity"
+	} else {
+		duration_text = fmt.Sprintf("%v", round(opts.duration))
+	}
+
+	// Let's check if there's anything on os.Stdin - otherwise it'll
+	// just hang, waiting for an EOF.
+	stat, _ := os.Stdin.Stat()
+	if (stat.Mode() & os.ModeCharDevice) != 0 {
+		msg := fmt.Errorf("Please provide targets on /dev/stdin, in HTTP or JSON format.")
+		log.Fatal(msg)
+	}
+
+	var body []byte
+	if *bodyFile != "" {
+		var err error
+		body, err = os.ReadFile(*bodyFile)
+		if err != nil {
+			msg := fmt.Errorf("Couldn't read -body file %q: %s", *bodyFile, err)
+			log.Fatal(msg)
+		}
+	}
+
+	targeter, err := newTargeter(*format, os.Stdin, body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Eagerly read all targets from os.Stdin.
+	targets, err := vegeta.ReadAllTargets(targeter)
+	if err != nil {
+		msg := fmt.Errorf("Couldn't figure out targets from /dev/stdin: %s", err)
+		log.Fatal(msg)
+	}
+	targeter = vegeta.NewStaticTargeter(targets...)
+
+	expandVars, err := loadVars(*varsFile, vars)
+	if err != nil {
+		log.Fatal(err)
+	}
+	targeter = templatingTargeter(targeter, expandVars)
+
+	var sink Sink
+	var live *liveBroadcaster
+	if *storageDir != "" {
+		fileSink, err := NewFileSink(*storageDir, 288) // 288 * segmentSpan(5m) = 24h retained
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer fileSink.Close()
+		sink = fileSink
+
+		if *httpListen != "" {
+			live = newLiveBroadcaster(time.Second)
+			srv := newMetricsServer(sink, live)
+			srv.listenAndServe(*httpListen)
+			fmt.Fprintf(os.Stderr, "📈  Serving metrics on %s\n", *httpListen)
+		}
+	}
+
+	dist, err := setupDistributed(*role, *peers, *listen, pacer, opts, waves, opts.duration)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var resumeFrom checkpoint
+	if *resume {
+		if *checkpointFile == "" {
+			log.Fatal(fmt.Errorf("-resume requires -checkpoint"))
+		}
+		resumeFrom, err = readCheckpoint(*checkpointFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if dist.duration > 0 {
+			dist.duration -= resumeFrom.Elapsed
+			if dist.duration <= 0 {
+				fmt.Fprintln(os.Stderr, "nothing left to resume: checkpoint already covers the full -duration")
+				return
+			}
+		}
+		fmt.Fprintf(os.Stderr, "⏪  Resuming from checkpoint at %s (%d hits)\n", round(resumeFrom.Elapsed), resumeFrom.Hits)
+	}
+
+	reloadable := newReloadablePacer(dist.pacer)
+	var finalPacer vegeta.Pacer = reloadable
+	if *resume {
+		finalPacer = offsetPacer{inner: reloadable, elapsed: resumeFrom.Elapsed, hits: resumeFrom.Hits}
+	}
+
+	attacker := vegeta.NewAttacker(
+		vegeta.KeepAlive(opts.keepalive),
+		vegeta.Timeout(opts.timeout),
+	)
+	enc := vegeta.NewEncoder(os.Stdout)
+
+	var metricsMu sync.Mutex
+	var metrics vegeta.Metrics
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(sigs)
+
+	go func() {
+		for sig := range sigs {
+			switch sig {
+			case syscall.SIGINT, syscall.SIGTERM:
+				fmt.Fprintln(os.Stderr, "\n🛑  Stopping gracefully, flushing results and report...")
+				attacker.Stop()
+				return
+			case syscall.SIGUSR1:
+				// Closing a live Metrics to report on it and then
+				// continuing to Add to it is the same pattern vegeta's
+				// own periodic reporting relies on.
+				metricsMu.Lock()
+				metrics.Close()
+				err := dumpReport(&metrics, *usr1Report)
+				metricsMu.Unlock()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "usr1 report: %s\n", err)
+				} else {
+					fmt.Fprintf(os.Stderr, "📝  Dumped intermediate report to %s\n", *usr1Report)
+				}
+			case syscall.SIGUSR2:
+				if *reloadConfigFile == "" {
+					fmt.Fprintln(os.Stderr, "SIGUSR2 received but -reload-config wasn't set, ignoring")
+					continue
+				}
+				newPacer, err := loadReloadConfig(*reloadConfigFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "reload: %s\n", err)
+					continue
+				}
+				reloadable.swap(newPacer)
+				fmt.Fprintf(os.Stderr, "🔄  Reloaded pacer: %v\n", newPacer)
+			}
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "🚀  Starting sine load test for %s\n", duration_text)
+	startedAt := time.Now()
+
+	var lastCheckpoint time.Time
+	var encodeFailed bool
+	local := attacker.Attack(targeter, finalPacer, dist.duration, "sine load")
+	for res := range dist.results(local) {
+		metricsMu.Lock()
+		metrics.Add(res)
+		hits := metrics.Requests
+		metricsMu.Unlock()
+
+		if sink != nil {
+			sink.Add(res)
+		}
+		if live != nil {
+			live.Add(res)
+		}
+		if err = enc.Encode(res); err != nil && !encodeFailed {
+			encodeFailed = true
+			fmt.Fprintf(os.Stderr, "error during attack, stopping: %s\n", err)
+			attacker.Stop()
+		}
+
+		if *checkpointFile != "" && time.Since(lastCheckpoint) >= 30*time.Second {
+			cp := checkpoint{Elapsed: resumeFrom.Elapsed + time.Since(startedAt), Hits: resumeFrom.Hits + hits, At: time.Now()}
+			if err := writeCheckpoint(*checkpointFile, cp); err != nil {
+				fmt.Fprintf(os.Stderr, "checkpoint: %s\n", err)
+			}
+			lastCheckpoint = time.Now()
+		}
+	}
+
+	metricsMu.Lock()
+	metrics.Close()
+	metricsMu.Unlock()
+
+	if *checkpointFile != "" {
+		cp := checkpoint{Elapsed: resumeFrom.Elapsed + time.Since(startedAt), Hits: resumeFrom.Hits + metrics.Requests, At: time.Now()}
+		if err := writeCheckpoint(*checkpointFile, cp); err != nil {
+			fmt.Fprintf(os.Stderr, "checkpoint: %s\n", err)
+		}
+	}
+
+	reporter := vegeta.NewTextReporter(&metrics)
+	reporter.Report(os.Stderr)
+
+	attackDuration := time.Since(startedAt)
+	fmt.Fprintf(os.Stderr, "✨  Variable load test completed in %v\n", round(attackDuration))
+}
+
+// dumpReport writes metrics as a text report to path. Caller must hold
+// metricsMu and have just called metrics.Close().
+func dumpReport(metrics *vegeta.Metrics, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return vegeta.NewTextReporter(metrics).Report(f)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// cmdEncode re-encodes one or more recorded attack result files between
+// gob, json and csv, merging multiple inputs chronologically the way
+// "sine report" and "sine plot" do.
+func cmdEncode(args []string) {
+	fs := flag.NewFlagSet("encode", flag.ExitOnError)
+
+	var inputs varsFlag
+	fs.Var(&inputs, "input", "Recorded results file to re-encode, may be repeated; merged chronologically")
+	inputFormat := fs.String("input-format", "auto", "Input format, one of \"gob\", \"json\", \"csv\" or \"auto\" to guess from each file's extension")
+	outputFormat := fs.String("output-format", "json", "Output format, one of \"gob\", \"json\" or \"csv\"")
+	output := fs.String("output", "", "File to write re-encoded results to, defaults to stdout")
+	fs.Parse(args)
+
+	if len(inputs) == 0 {
+		log.Fatal(fmt.Errorf("sine encode: at least one -input file is required"))
+	}
+
+	results, err := readResults(inputs, *inputFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(fmt.Errorf("sine encode: couldn't create -output file: %s", err))
+		}
+		defer f.Close()
+		out = f
+	}
+
+	enc, err := encoderFor(*outputFormat, out)
+	if err != nil {
+		log.Fatal(fmt.Errorf("sine encode: %s", err))
+	}
+
+	for _, res := range results {
+		if err := enc.Encode(res); err != nil {
+			log.Fatal(fmt.Errorf("sine encode: %s", err))
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"os"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// plotBucketSpan is the window actual hit counts are aggregated into
+// before being compared against the theoretical rate curve.
+const plotBucketSpan = time.Second
+
+// plotPoint is one sample on the chart: seconds since the run started,
+// and a value in the appropriate unit for its series.
+type plotPoint struct {
+	T float64
+	V float64
+}
+
+// plotData is everything the HTML template needs to render the chart.
+type plotData struct {
+	Latency    []plotPoint // ms
+	ActualRate []plotPoint // hits/sec, bucketed
+	TheoryRate []plotPoint // hits/sec, from the supplied pacer config
+	HasTheory  bool
+}
+
+// cmdPlot renders a recorded attack as a self-contained HTML chart of
+// latency and request rate over time, optionally overlaid with the
+// theoretical rate curve of the pacer that produced the run so users can
+// visually compare the requested sine wave against what was achieved.
+func cmdPlot(args []string) {
+	fs := flag.NewFlagSet("plot", flag.ExitOnError)
+
+	var inputs varsFlag
+	fs.Var(&inputs, "input", "Recorded results file to plot, may be repeated; merged chronologically")
+	format := fs.String("format", "auto", "Input format, one of \"gob\", \"json\", \"csv\" or \"auto\" to guess from each file's extension")
+	output := fs.String("output", "plot.html", "HTML file to write the chart to")
+
+	opts := paceOpts{}
+	fs.DurationVar(&opts.period, "period", 10*time.Minute, "Period of the sine wave that produced this run, for the overlay curve")
+	fs.IntVar(&opts.mean, "mean", 0, "Mean req/1s of the sine wave that produced this run, for the overlay curve")
+	fs.IntVar(&opts.amplitude, "amplitude", 0, "Amplitude of the sine wave that produced this run, for the overlay curve")
+	fs.Float64Var(&opts.startAt, "startAt", 0, "Phase of the sine wave that produced this run, for the overlay curve")
+	var waves wavesFlag
+	fs.Var(&waves, "wave", "Component of the compound pacer that produced this run, for the overlay curve; same syntax as \"sine attack -wave\"")
+	fs.Parse(args)
+
+	if len(inputs) == 0 {
+		log.Fatal(fmt.Errorf("sine plot: at least one -input file is required"))
+	}
+
+	results, err := readResults(inputs, *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(results) == 0 {
+		log.Fatal(fmt.Errorf("sine plot: no results to plot"))
+	}
+
+	data := buildPlotData(results)
+
+	if opts.mean > 0 || len(waves) > 0 {
+		pacer, err := buildPacer(opts, waves)
+		if err != nil {
+			log.Fatal(fmt.Errorf("sine plot: %s", err))
+		}
+		data.TheoryRate = theoryCurve(pacer, results[len(results)-1].Timestamp.Sub(results[0].Timestamp))
+		data.HasTheory = true
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatal(fmt.Errorf("sine plot: couldn't create -output file: %s", err))
+	}
+	defer f.Close()
+
+	if err := plotTemplate.Execute(f, data); err != nil {
+		log.Fatal(fmt.Errorf("sine plot: %s", err))
+	}
+
+	fmt.Fprintf(os.Stderr, "📊  Wrote plot to %s\n", *output)
+}
+
+// buildPlotData turns raw results into the latency series and a
+// bucketed actual-rate series, both relative to the first result's time.
+func buildPlotData(results []*vegeta.Result) plotData {
+	start := results[0].Timestamp
+
+	var data plotData
+	var bucketCount int
+	bucketStart := start
+
+	for _, res := range results {
+		t := res.Timestamp.Sub(start).Seconds()
+		data.Latency = append(data.Latency, plotPoint{T: t, V: float64(res.Latency) / float64(time.Millisecond)})
+
+		for res.Timestamp.Sub(bucketStart) >= plotBucketSpan {
+			data.ActualRate = append(data.ActualRate, plotPoint{
+				T: bucketStart.Sub(start).Seconds(),
+				V: float64(bucketCount) / plotBucketSpan.Seconds(),
+			})
+			bucketCount = 0
+			bucketStart = bucketStart.Add(plotBucketSpan)
+		}
+		bucketCount++
+	}
+	data.ActualRate = append(data.ActualRate, plotPoint{
+		T: bucketStart.Sub(start).Seconds(),
+		V: float64(bucketCount) / plotBucketSpan.Seconds(),
+	})
+
+	return data
+}
+
+// theoryCurve samples pacer's instantaneous rate once per bucket across
+// the run's duration, for the overlay line. Every vegeta.Pacer exports
+// Rate, so this needs no type switch on the concrete pacer buildPacer
+// returned.
+func theoryCurve(pacer vegeta.Pacer, duration time.Duration) []plotPoint {
+	var curve []plotPoint
+	for t := time.Duration(0); t <= duration; t += plotBucketSpan {
+		curve = append(curve, plotPoint{T: t.Seconds(), V: pacer.Rate(t)})
+	}
+	return curve
+}
+
+const (
+	svgWidth  = 900
+	svgHeight = 220
+)
+
+// svgSeries is one polyline to render: its points and the CSS class that
+// styles it.
+type svgSeries struct {
+	points []plotPoint
+	class  string
+}
+
+// renderSVG scales every series onto a shared [0,svgWidth]x[0,svgHeight]
+// viewBox, using the combined min/max of all series for each axis, and
+// draws each as a <polyline>.
+func renderSVG(serieses ...svgSeries) template.HTML {
+	minT, maxT := 0.0, 0.0
+	minV, maxV := 0.0, 0.0
+	any := false
+
+	for _, s := range serieses {
+		for _, p := range s.points {
+			if !any {
+				minT, maxT, minV, maxV = p.T, p.T, p.V, p.V
+				any = true
+			}
+			minT, maxT = math.Min(minT, p.T), math.Max(maxT, p.T)
+			minV, maxV = math.Min(minV, p.V), math.Max(maxV, p.V)
+		}
+	}
+	if maxT == minT {
+		maxT = minT + 1
+	}
+	if maxV == minV {
+		maxV = minV + 1
+	}
+
+	out := fmt.Sprintf(`<svg viewBox="0 0 %d %d" width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">`, svgWidth, svgHeight, svgWidth, svgHeight)
+	for _, s := range serieses {
+		out += fmt.Sprintf(`<polyline class="%s" points="`, s.class)
+		for _, p := range s.points {
+			x := (p.T - minT) / (maxT - minT) * svgWidth
+			y := svgHeight - (p.V-minV)/(maxV-minV)*svgHeight
+			out += fmt.Sprintf("%.1f,%.1f ", x, y)
+		}
+		out += `"/>`
+	}
+	out += `</svg>`
+
+	return template.HTML(out)
+}
+
+var plotFuncs = template.FuncMap{
+	"svgLine": func(actual, theory []plotPoint) template.HTML {
+		return renderSVG(svgSeries{actual, "actual"}, svgSeries{theory, "theory"})
+	},
+	"svgSingle": func(points []plotPoint) template.HTML {
+		return renderSVG(svgSeries{points, "latency"})
+	},
+}
+
+var plotTemplate = template.Must(template.New("plot").Funcs(plotFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>sine attack plot</title>
+<style>
+  body { font-family: sans-serif; margin: 2em; }
+  svg { border: 1px solid #ccc; }
+  .actual { stroke: #2563eb; fill: none; stroke-width: 1.5; }
+  .theory { stroke: #dc2626; fill: none; stroke-width: 1.5; stroke-dasharray: 4 3; }
+  .latency { stroke: #16a34a; fill: none; stroke-width: 1; }
+  h2 { font-size: 1em; margin-bottom: 0.2em; }
+</style>
+</head>
+<body>
+<h1>sine attack plot</h1>
+
+<h2>Request rate (hits/sec) — actual (solid) vs theoretical (dashed)</h2>
+{{ svgLine .ActualRate .TheoryRate }}
+
+<h2>Latency (ms)</h2>
+{{ svgSingle .Latency }}
+
+</body>
+</html>
+`))
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// cmdReport builds a report from one or more recorded "sine attack"
+// result files, merging them chronologically first so a sharded or
+// resumed run can be analyzed as a single whole.
+func cmdReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+
+	var inputs varsFlag
+	fs.Var(&inputs, "input", "Recorded results file to report on, may be repeated; merged chronologically")
+	format := fs.String("format", "auto", "Input format, one of \"gob\", \"json\", \"csv\" or \"auto\" to guess from each file's extension")
+	typ := fs.String("type", "text", "Report type: \"text\", \"json\", \"hist\" or \"hdrhistogram\"")
+	buckets := fs.String("buckets", "", "Comma-separated bucket boundaries for -type hist, e.g. \"0,10ms,50ms,100ms,500ms\"")
+	output := fs.String("output", "", "File to write the report to, defaults to stdout")
+	fs.Parse(args)
+
+	if len(inputs) == 0 {
+		log.Fatal(fmt.Errorf("sine report: at least one -input file is required"))
+	}
+
+	results, err := readResults(inputs, *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var metrics vegeta.Metrics
+	if *typ == "hist" {
+		if *buckets == "" {
+			log.Fatal(fmt.Errorf("sine report: -type hist requires -buckets"))
+		}
+		// vegeta.Buckets.UnmarshalText wants its value bracketed, e.g.
+		// "[0,10ms,50ms]"; accept the bare comma-separated form too.
+		raw := *buckets
+		if !strings.HasPrefix(raw, "[") {
+			raw = "[" + raw + "]"
+		}
+		var bs vegeta.Buckets
+		if err := bs.UnmarshalText([]byte(raw)); err != nil {
+			log.Fatal(fmt.Errorf("sine report: invalid -buckets: %s", err))
+		}
+		// Histogram must be set before Add is called below: that's the
+		// only place per-result latencies get bucketed.
+		metrics.Histogram = &vegeta.Histogram{Buckets: bs}
+	}
+
+	for _, res := range results {
+		metrics.Add(res)
+	}
+	metrics.Close()
+
+	var reporter vegeta.Reporter
+	switch *typ {
+	case "text":
+		reporter = vegeta.NewTextReporter(&metrics)
+	case "json":
+		reporter = vegeta.NewJSONReporter(&metrics)
+	case "hist":
+		reporter = vegeta.NewHistogramReporter(metrics.Histogram)
+	case "hdrhistogram":
+		reporter = vegeta.NewHDRHistogramPlotReporter(&metrics)
+	default:
+		log.Fatal(fmt.Errorf("sine report: unknown -type %q, want \"text\", \"json\", \"hist\" or \"hdrhistogram\"", *typ))
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(fmt.Errorf("sine report: couldn't create -output file: %s", err))
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := reporter.Report(out); err != nil {
+		log.Fatal(fmt.Errorf("sine report: %s", err))
+	}
+}
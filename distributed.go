@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// controlMsg is what a leader sends a follower over its control
+// connection to synchronize a sharded attack: when to start, which
+// shard the follower is, how many shards exist in total (so the
+// follower can scale its own pacer down to 1/shardCount), how long to
+// run, and the pacer config itself, so a single sine-wave spec can be
+// split across processes without double-counting the aggregate rate and
+// without relying on every follower's CLI flags staying in sync with
+// the leader's by hand.
+type controlMsg struct {
+	StartAt    time.Time
+	ShardID    int
+	ShardCount int
+	Duration   time.Duration
+	Pacer      pacerSpec
+}
+
+// pacerSpec is the JSON-serializable form of whatever -wave/-period/
+// -mean/-amplitude/-startAt flags the leader was started with, so a
+// follower can rebuild the identical pacer instead of trusting its own,
+// independently-specified flags not to have drifted.
+type pacerSpec struct {
+	Waves     []string
+	Period    time.Duration
+	Mean      int
+	Amplitude int
+	StartAt   float64
+}
+
+func pacerSpecFrom(opts paceOpts, waves []string) pacerSpec {
+	return pacerSpec{Waves: waves, Period: opts.period, Mean: opts.mean, Amplitude: opts.amplitude, StartAt: opts.startAt}
+}
+
+func (s pacerSpec) paceOpts() paceOpts {
+	return paceOpts{period: s.Period, mean: s.Mean, amplitude: s.Amplitude, startAt: s.StartAt}
+}
+
+// leaderStartDelay gives peers enough time to receive and act on the
+// control message before the synchronized start time arrives.
+const leaderStartDelay = 5 * time.Second
+
+// dialFollowers connects to every peer address, sends it its shard
+// assignment, and returns one connection per peer plus the synchronized
+// start time the leader itself should also wait for.
+func dialFollowers(peers []string, duration time.Duration, pacer pacerSpec) ([]net.Conn, time.Time, error) {
+	startAt := time.Now().Add(leaderStartDelay)
+	shardCount := len(peers) + 1 // +1 for the leader's own shard 0
+
+	conns := make([]net.Conn, 0, len(peers))
+	for i, addr := range peers {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("couldn't connect to peer %q: %s", addr, err)
+		}
+
+		msg := controlMsg{StartAt: startAt, ShardID: i + 1, ShardCount: shardCount, Duration: duration, Pacer: pacer}
+		if err := json.NewEncoder(conn).Encode(msg); err != nil {
+			return nil, time.Time{}, fmt.Errorf("couldn't send shard assignment to %q: %s", addr, err)
+		}
+
+		conns = append(conns, conn)
+	}
+
+	return conns, startAt, nil
+}
+
+// awaitLeader listens on addr for the leader's single control
+// connection and returns it along with the shard assignment it carried.
+func awaitLeader(addr string) (net.Conn, controlMsg, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, controlMsg{}, fmt.Errorf("couldn't listen on %q: %s", addr, err)
+	}
+	defer ln.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		return nil, controlMsg{}, fmt.Errorf("couldn't accept leader connection: %s", err)
+	}
+
+	var msg controlMsg
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&msg); err != nil {
+		return nil, controlMsg{}, fmt.Errorf("couldn't read shard assignment: %s", err)
+	}
+
+	return conn, msg, nil
+}
+
+// streamResults gob-decodes vegeta.Results off a follower's connection
+// until it's closed, for the leader to merge into its own metrics.
+func streamResults(conn net.Conn) <-chan *vegeta.Result {
+	out := make(chan *vegeta.Result)
+	go func() {
+		defer close(out)
+		dec := gob.NewDecoder(bufio.NewReader(conn))
+		for {
+			var res vegeta.Result
+			if err := dec.Decode(&res); err != nil {
+				return
+			}
+			out <- &res
+		}
+	}()
+	return out
+}
+
+// forwardResults relays every result from in to both the returned
+// channel (for this process's own local metrics/sink/encoder) and, gob-
+// encoded, to conn (for the leader to merge), closing conn once in is
+// drained so the leader knows this shard is done.
+func forwardResults(conn net.Conn, in <-chan *vegeta.Result) <-chan *vegeta.Result {
+	out := make(chan *vegeta.Result)
+	go func() {
+		defer close(out)
+		defer conn.Close()
+		enc := gob.NewEncoder(conn)
+		for res := range in {
+			if err := enc.Encode(res); err != nil {
+				fmt.Fprintf(os.Stderr, "distributed: couldn't forward result to leader: %s\n", err)
+			}
+			out <- res
+		}
+	}()
+	return out
+}
+
+// fanIn merges any number of result channels into one, closing it once
+// every source channel has closed.
+func fanIn(chans ...<-chan *vegeta.Result) <-chan *vegeta.Result {
+	out := make(chan *vegeta.Result)
+	var wg sync.WaitGroup
+	wg.Add(len(chans))
+	for _, ch := range chans {
+		go func(ch <-chan *vegeta.Result) {
+			defer wg.Done()
+			for res := range ch {
+				out <- res
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// distributedSetup is what main derives from -role/-peers/-listen before
+// running its own shard of the attack: the (possibly scaled) pacer and
+// duration to use, any peer connections to stream results from (leader),
+// and the connection to stream this process's own results to (follower).
+type distributedSetup struct {
+	pacer      vegeta.Pacer
+	duration   time.Duration
+	peerConns  []net.Conn
+	leaderConn net.Conn
+}
+
+// setupDistributed wires up -role leader/follower: a leader dials every
+// peer, broadcasts its own pacer config along with shard assignments so
+// followers can't drift from it, a follower waits for the leader's
+// assignment and rebuilds its pacer from that broadcast config rather
+// than its own -wave/-period/-mean/-amplitude/-startAt flags, and both
+// scale their own pacer down to 1/shardCount and wait for the
+// synchronized start time so the aggregate rate across every shard
+// matches the originally requested one.
+func setupDistributed(role, peersCSV, listenAddr string, pacer vegeta.Pacer, opts paceOpts, waves []string, duration time.Duration) (*distributedSetup, error) {
+	switch role {
+	case "":
+		return &distributedSetup{pacer: pacer, duration: duration}, nil
+
+	case "leader":
+		var peers []string
+		for _, p := range strings.Split(peersCSV, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				peers = append(peers, p)
+			}
+		}
+
+		conns, startAt, err := dialFollowers(peers, duration, pacerSpecFrom(opts, waves))
+		if err != nil {
+			return nil, err
+		}
+
+		time.Sleep(time.Until(startAt))
+		return &distributedSetup{
+			pacer:     scalePacer(pacer, len(peers)+1),
+			duration:  duration,
+			peerConns: conns,
+		}, nil
+
+	case "follower":
+		if listenAddr == "" {
+			return nil, fmt.Errorf("-role follower requires -listen")
+		}
+
+		conn, msg, err := awaitLeader(listenAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		followerPacer, err := buildPacer(msg.Pacer.paceOpts(), msg.Pacer.Waves)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't build pacer from leader's broadcast config: %s", err)
+		}
+
+		time.Sleep(time.Until(msg.StartAt))
+		return &distributedSetup{
+			pacer:      scalePacer(followerPacer, msg.ShardCount),
+			duration:   msg.Duration,
+			leaderConn: conn,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown -role %q, want \"leader\" or \"follower\"", role)
+	}
+}
+
+// results wraps the local attack's result channel so that a follower also
+// streams every result to its leader, and a leader also merges in every
+// result its followers stream back, presenting a single channel either
+// way for main's usual metrics/sink/encoder loop.
+func (d *distributedSetup) results(local <-chan *vegeta.Result) <-chan *vegeta.Result {
+	if d.leaderConn != nil {
+		return forwardResults(d.leaderConn, local)
+	}
+	if len(d.peerConns) > 0 {
+		chans := make([]<-chan *vegeta.Result, 0, len(d.peerConns)+1)
+		chans = append(chans, local)
+		for _, conn := range d.peerConns {
+			chans = append(chans, streamResults(conn))
+		}
+		return fanIn(chans...)
+	}
+	return local
+}
+
+// scalePacer divides p's rate by shardCount, the way each follower in a
+// sharded attack needs to so the aggregate across all shards matches the
+// originally requested rate.
+func scalePacer(p vegeta.Pacer, shardCount int) vegeta.Pacer {
+	if shardCount <= 1 {
+		return p
+	}
+
+	switch v := p.(type) {
+	case vegeta.SinePacer:
+		v.Mean.Per *= time.Duration(shardCount)
+		v.Amp.Per *= time.Duration(shardCount)
+		return v
+	case *CompoundPacer:
+		return v.scaled(float64(shardCount))
+	default:
+		return p
+	}
+}
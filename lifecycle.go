@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// reloadablePacer wraps another Pacer behind a mutex so SIGUSR2 can swap
+// in a new one between ticks. Pace is purely a function of the elapsed
+// time and cumulative hit count the attacker passes in on every call, so
+// a pacer swapped in mid-run still sees the same (elapsed, hits) the old
+// one would have - the cumulative hit count invariant vegeta's Attacker
+// relies on is preserved across the swap.
+type reloadablePacer struct {
+	mu    sync.Mutex
+	inner vegeta.Pacer
+}
+
+func newReloadablePacer(p vegeta.Pacer) *reloadablePacer {
+	return &reloadablePacer{inner: p}
+}
+
+func (r *reloadablePacer) Pace(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+	r.mu.Lock()
+	p := r.inner
+	r.mu.Unlock()
+	return p.Pace(elapsed, hits)
+}
+
+func (r *reloadablePacer) Rate(elapsed time.Duration) float64 {
+	r.mu.Lock()
+	p := r.inner
+	r.mu.Unlock()
+	return p.Rate(elapsed)
+}
+
+func (r *reloadablePacer) swap(p vegeta.Pacer) {
+	r.mu.Lock()
+	r.inner = p
+	r.mu.Unlock()
+}
+
+var _ vegeta.Pacer = (*reloadablePacer)(nil)
+
+// offsetPacer shifts the elapsed time and hit count an inner Pacer sees
+// by a fixed amount, so a resumed attack's fresh Attacker (which always
+// starts counting from zero) continues the rate curve and hit count from
+// where a previous run left off instead of restarting it.
+type offsetPacer struct {
+	inner   vegeta.Pacer
+	elapsed time.Duration
+	hits    uint64
+}
+
+func (o offsetPacer) Pace(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+	return o.inner.Pace(elapsed+o.elapsed, hits+o.hits)
+}
+
+func (o offsetPacer) Rate(elapsed time.Duration) float64 {
+	return o.inner.Rate(elapsed + o.elapsed)
+}
+
+var _ vegeta.Pacer = offsetPacer{}
+
+// checkpoint is what -checkpoint periodically saves during an attack and
+// -resume reads back in, so a multi-hour run interrupted by a crash or a
+// deliberate SIGTERM can be continued rather than restarted from scratch.
+type checkpoint struct {
+	Elapsed time.Duration `json:"elapsed"`
+	Hits    uint64        `json:"hits"`
+	At      time.Time     `json:"at"`
+}
+
+// writeCheckpoint saves cp to path, writing to a temp file first and
+// renaming it into place so a reader never sees a half-written file.
+func writeCheckpoint(path string, cp checkpoint) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("couldn't write checkpoint: %s", err)
+	}
+	if err := json.NewEncoder(f).Encode(cp); err != nil {
+		f.Close()
+		return fmt.Errorf("couldn't write checkpoint: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("couldn't write checkpoint: %s", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func readCheckpoint(path string) (checkpoint, error) {
+	var cp checkpoint
+	f, err := os.Open(path)
+	if err != nil {
+		return cp, fmt.Errorf("couldn't read checkpoint %q: %s", path, err)
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&cp); err != nil {
+		return cp, fmt.Errorf("couldn't parse checkpoint %q: %s", path, err)
+	}
+	return cp, nil
+}
+
+// reloadConfig is the shape of the -reload-config file SIGUSR2 re-reads
+// to swap in a new sine pacer without stopping the attack.
+type reloadConfig struct {
+	Period    string  `json:"period"`
+	Mean      int     `json:"mean"`
+	Amplitude int     `json:"amplitude"`
+	StartAt   float64 `json:"startAt"`
+}
+
+func loadReloadConfig(path string) (vegeta.Pacer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read -reload-config %q: %s", path, err)
+	}
+	defer f.Close()
+
+	var cfg reloadConfig
+	if err := json.NewDecoder(f).Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("couldn't parse -reload-config %q: %s", path, err)
+	}
+
+	period, err := time.ParseDuration(cfg.Period)
+	if err != nil {
+		return nil, fmt.Errorf("invalid period in -reload-config: %s", err)
+	}
+
+	sine := vegeta.SinePacer{
+		Period:  period,
+		Mean:    vegeta.Rate{Freq: cfg.Mean, Per: time.Second},
+		Amp:     vegeta.Rate{Freq: cfg.Amplitude, Per: time.Second},
+		StartAt: cfg.StartAt,
+	}
+	if invalid(sine) {
+		return nil, fmt.Errorf("-reload-config %q is invalid: Mean must be positive, Amplitude must be smaller than Mean", path)
+	}
+
+	return sine, nil
+}
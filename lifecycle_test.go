@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	want := checkpoint{Elapsed: 90 * time.Second, Hits: 42, At: time.Now().Truncate(time.Second).UTC()}
+
+	if err := writeCheckpoint(path, want); err != nil {
+		t.Fatalf("writeCheckpoint: %s", err)
+	}
+
+	got, err := readCheckpoint(path)
+	if err != nil {
+		t.Fatalf("readCheckpoint: %s", err)
+	}
+	if got.Elapsed != want.Elapsed || got.Hits != want.Hits || !got.At.Equal(want.At) {
+		t.Errorf("readCheckpoint = %+v, want %+v", got, want)
+	}
+}
+
+func TestReloadablePacerSwap(t *testing.T) {
+	slow := vegeta.ConstantPacer{Freq: 1, Per: time.Second}
+	fast := vegeta.ConstantPacer{Freq: 100, Per: time.Second}
+
+	rp := newReloadablePacer(slow)
+	if got, want := rp.Rate(0), slow.Rate(0); got != want {
+		t.Errorf("Rate before swap = %v, want %v", got, want)
+	}
+
+	rp.swap(fast)
+	if got, want := rp.Rate(0), fast.Rate(0); got != want {
+		t.Errorf("Rate after swap = %v, want %v", got, want)
+	}
+}
+
+func TestOffsetPacerShiftsElapsedAndHits(t *testing.T) {
+	inner := vegeta.ConstantPacer{Freq: 10, Per: time.Second}
+	op := offsetPacer{inner: inner, elapsed: 5 * time.Second, hits: 50}
+
+	wantWait, wantStop := inner.Pace(5*time.Second+time.Second, 50+1)
+	gotWait, gotStop := op.Pace(time.Second, 1)
+	if gotWait != wantWait || gotStop != wantStop {
+		t.Errorf("Pace(1s, 1) = (%v, %v), want (%v, %v)", gotWait, gotStop, wantWait, wantStop)
+	}
+
+	if got, want := op.Rate(time.Second), inner.Rate(6*time.Second); got != want {
+		t.Errorf("Rate(1s) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// liveBroadcaster aggregates results into fixed windows and fans each
+// completed window out to subscribers as it closes, for the /metrics/live
+// SSE endpoint. Only the current window is ever held in memory, so this
+// stays bounded no matter how long the attack runs.
+type liveBroadcaster struct {
+	window time.Duration
+
+	mu         sync.Mutex
+	latencies  []time.Duration
+	successes  int
+	windowFrom time.Time
+	subs       map[chan Bucket]struct{}
+}
+
+func newLiveBroadcaster(window time.Duration) *liveBroadcaster {
+	b := &liveBroadcaster{
+		window:     window,
+		windowFrom: time.Now(),
+		subs:       map[chan Bucket]struct{}{},
+	}
+	go b.loop()
+	return b
+}
+
+func (b *liveBroadcaster) loop() {
+	t := time.NewTicker(b.window)
+	defer t.Stop()
+	for range t.C {
+		b.flush()
+	}
+}
+
+func (b *liveBroadcaster) Add(res *vegeta.Result) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.latencies = append(b.latencies, res.Latency)
+	if res.Error == "" {
+		b.successes++
+	}
+}
+
+func (b *liveBroadcaster) flush() {
+	b.mu.Lock()
+	bucket := Bucket{Start: b.windowFrom, Count: len(b.latencies)}
+	if bucket.Count > 0 {
+		bucket.SuccessRatio = float64(b.successes) / float64(bucket.Count)
+		bucket.Mean, bucket.P50, bucket.P95, bucket.P99 = summarize(b.latencies)
+	}
+	b.latencies = nil
+	b.successes = 0
+	b.windowFrom = time.Now()
+	subs := make([]chan Bucket, 0, len(b.subs))
+	for ch := range b.subs {
+		subs = append(subs, ch)
+	}
+	b.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- bucket:
+		default: // slow subscriber, drop the window rather than block the attack
+		}
+	}
+}
+
+func (b *liveBroadcaster) subscribe() chan Bucket {
+	ch := make(chan Bucket, 8)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *liveBroadcaster) unsubscribe(ch chan Bucket) {
+	b.mu.Lock()
+	delete(b.subs, ch)
+	b.mu.Unlock()
+	close(ch)
+}
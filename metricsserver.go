@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// metricsServer exposes a Sink's stored results and a liveBroadcaster's
+// in-flight windows over HTTP, so a dashboard can follow the sine-wave
+// attack's progress during or after the run.
+type metricsServer struct {
+	sink Sink
+	live *liveBroadcaster
+}
+
+func newMetricsServer(sink Sink, live *liveBroadcaster) *metricsServer {
+	return &metricsServer{sink: sink, live: live}
+}
+
+func (m *metricsServer) mux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics/range", m.handleRange)
+	mux.HandleFunc("/metrics/live", m.handleLive)
+	return mux
+}
+
+// listenAndServe starts the metrics HTTP server in the background and
+// returns immediately; serve errors are logged to stderr since they
+// shouldn't abort an in-progress attack.
+func (m *metricsServer) listenAndServe(addr string) {
+	go func() {
+		if err := http.ListenAndServe(addr, m.mux()); err != nil {
+			fmt.Fprintf(os.Stderr, "metrics server: %s\n", err)
+		}
+	}()
+}
+
+func (m *metricsServer) handleRange(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	from, err := parseTime(q.Get("from"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid from: %s", err), http.StatusBadRequest)
+		return
+	}
+	to, err := parseTime(q.Get("to"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid to: %s", err), http.StatusBadRequest)
+		return
+	}
+	step, err := time.ParseDuration(q.Get("step"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid step: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	metric := q.Get("metric")
+	if metric == "" {
+		metric = "latency"
+	}
+
+	buckets, err := m.sink.Range(metric, from, to, step)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buckets)
+}
+
+func (m *metricsServer) handleLive(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := m.live.subscribe()
+	defer m.live.unsubscribe(ch)
+
+	for {
+		select {
+		case bucket, ok := <-ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(bucket)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, fmt.Errorf("must be set")
+	}
+	return time.Parse(time.RFC3339, s)
+}
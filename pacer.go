@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// hitsPerNs returns the attack rate this ConstantPacer represents, in
+// fractional hits per nanosecond.
+func hitsPerNs(cp vegeta.ConstantPacer) float64 {
+	return float64(cp.Freq) / float64(cp.Per)
+}
+
+func invalid(sp vegeta.SinePacer) bool {
+	return sp.Period <= 0 || hitsPerNs(sp.Mean) <= 0 || hitsPerNs(sp.Amp) >= hitsPerNs(sp.Mean)
+}
+
+// wave is one component of a CompoundPacer: something that knows its own
+// instantaneous rate in hits/sec at elapsed time t, and the exact number
+// of hits it contributes between 0 and t (its integral), so CompoundPacer
+// can sum components exactly instead of approximating.
+type wave interface {
+	Rate(t time.Duration) float64
+	integral(t time.Duration) float64
+	scaled(factor float64) wave
+}
+
+// sineWave is a periodic component, e.g. "sine:period=10m,mean=100,amp=30,phase=0".
+type sineWave struct {
+	period time.Duration
+	mean   float64
+	amp    float64
+	phase  float64
+}
+
+func (s sineWave) Rate(t time.Duration) float64 {
+	return s.mean + s.amp*math.Sin(2*math.Pi*t.Seconds()/s.period.Seconds()+s.phase)
+}
+
+func (s sineWave) integral(t time.Duration) float64 {
+	w := 2 * math.Pi / s.period.Seconds()
+	return s.mean*t.Seconds() - s.amp/w*(math.Cos(w*t.Seconds()+s.phase)-math.Cos(s.phase))
+}
+
+func (s sineWave) scaled(factor float64) wave {
+	s.mean /= factor
+	s.amp /= factor
+	return s
+}
+
+// rampWave is a linear component that rises (or falls) from start to end
+// over duration and then holds at end, e.g. "ramp:start=10,end=200,duration=1h".
+type rampWave struct {
+	start, end float64
+	duration   time.Duration
+}
+
+func (r rampWave) Rate(t time.Duration) float64 {
+	if t >= r.duration {
+		return r.end
+	}
+	return r.start + (r.end-r.start)*t.Seconds()/r.duration.Seconds()
+}
+
+func (r rampWave) integral(t time.Duration) float64 {
+	d := r.duration.Seconds()
+	if t.Seconds() >= d {
+		return r.start*d + (r.end-r.start)*d/2 + r.end*(t.Seconds()-d)
+	}
+	ts := t.Seconds()
+	return r.start*ts + (r.end-r.start)*ts*ts/(2*d)
+}
+
+func (r rampWave) scaled(factor float64) wave {
+	r.start /= factor
+	r.end /= factor
+	return r
+}
+
+// squareWave alternates between high and low every period, spending duty
+// (a 0..1 fraction of period) at high, e.g. "square:period=5m,low=50,high=150,duty=0.3".
+type squareWave struct {
+	period    time.Duration
+	low, high float64
+	duty      float64
+}
+
+func (s squareWave) Rate(t time.Duration) float64 {
+	phase := math.Mod(t.Seconds(), s.period.Seconds())
+	if phase < s.duty*s.period.Seconds() {
+		return s.high
+	}
+	return s.low
+}
+
+func (s squareWave) integral(t time.Duration) float64 {
+	period := s.period.Seconds()
+	high := s.duty * period
+	low := period - high
+	n := math.Floor(t.Seconds() / period)
+	r := t.Seconds() - n*period
+
+	whole := n * (s.high*high + s.low*low)
+	partial := s.high*math.Min(r, high) + s.low*math.Max(0, r-high)
+
+	return whole + partial
+}
+
+func (s squareWave) scaled(factor float64) wave {
+	s.low /= factor
+	s.high /= factor
+	return s
+}
+
+// CompoundPacer satisfies vegeta.Pacer by summing the instantaneous rate
+// of each of its component waves to determine hits(t), then inverting
+// that sum to find the next hit's time, the same way vegeta's own
+// SinePacer derives wait times from a rate function.
+type CompoundPacer struct {
+	waves []wave
+}
+
+// NewCompoundPacer builds a CompoundPacer from its component waves. It
+// returns an error if the combined rate is non-positive at any of the
+// points validate samples over the first 24h, since a Pacer can't invert
+// a rate function that reaches zero or goes negative. Dips between those
+// samples, or after 24h, are not detected.
+func NewCompoundPacer(waves ...wave) (*CompoundPacer, error) {
+	cp := &CompoundPacer{waves: waves}
+	if err := cp.validate(); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// Rate implements vegeta.Pacer, returning the combined instantaneous
+// rate of every component wave in hits/sec.
+func (cp *CompoundPacer) Rate(t time.Duration) float64 {
+	var r float64
+	for _, w := range cp.waves {
+		r += w.Rate(t)
+	}
+	return r
+}
+
+func (cp *CompoundPacer) integral(t time.Duration) float64 {
+	var sum float64
+	for _, w := range cp.waves {
+		sum += w.integral(t)
+	}
+	return sum
+}
+
+// scaled returns a CompoundPacer whose combined rate is this one's
+// divided by factor, by scaling each component wave in turn.
+func (cp *CompoundPacer) scaled(factor float64) *CompoundPacer {
+	waves := make([]wave, len(cp.waves))
+	for i, w := range cp.waves {
+		waves[i] = w.scaled(factor)
+	}
+	return &CompoundPacer{waves: waves}
+}
+
+// validate samples the combined rate across a generous window to catch
+// the common mistake of an amplitude larger than its mean, which would
+// otherwise only surface as a confusing stall mid-attack.
+func (cp *CompoundPacer) validate() error {
+	const samples = 10000
+	window := 24 * time.Hour
+	for i := 0; i <= samples; i++ {
+		t := time.Duration(i) * window / samples
+		if cp.Rate(t) <= 0 {
+			return fmt.Errorf("compound pacer rate is non-positive at t=%s; check amplitudes against means", t)
+		}
+	}
+	return nil
+}
+
+// Pace implements vegeta.Pacer. It finds the elapsed time at which the
+// cumulative expected hit count would reach hits+1 by inverting the
+// (monotonically increasing, since rate is always positive) integral via
+// Newton's method, falling back to bisection if Newton overshoots.
+func (cp *CompoundPacer) Pace(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+	expected := cp.integral(elapsed)
+	if hits == 0 || float64(hits) < expected {
+		return 0, false
+	}
+
+	target := float64(hits + 1)
+	at := cp.invert(target)
+	if at < elapsed {
+		return 0, false
+	}
+	return at - elapsed, false
+}
+
+// invert solves cp.integral(t) == target for t >= 0, using Newton's
+// method with a bisection safeguard since integral is monotonic.
+func (cp *CompoundPacer) invert(target float64) time.Duration {
+	lo, hi := 0.0, 1.0
+	for cp.integral(time.Duration(hi*float64(time.Second))) < target {
+		hi *= 2
+	}
+
+	t := (lo + hi) / 2
+	for i := 0; i < 100; i++ {
+		f := cp.integral(time.Duration(t*float64(time.Second))) - target
+		if math.Abs(f) < 1e-6 {
+			break
+		}
+		if f > 0 {
+			hi = t
+		} else {
+			lo = t
+		}
+
+		fp := cp.Rate(time.Duration(t * float64(time.Second)))
+		next := t
+		if fp > 0 {
+			next = t - f/fp
+		}
+		if next <= lo || next >= hi {
+			next = (lo + hi) / 2 // Newton stepped outside the bracket, bisect instead
+		}
+		t = next
+	}
+
+	return time.Duration(t * float64(time.Second))
+}
+
+var _ vegeta.Pacer = (*CompoundPacer)(nil)
+
+// wavesFlag collects repeated -wave flags in the order they were given.
+type wavesFlag []string
+
+func (w *wavesFlag) String() string { return strings.Join(*w, ",") }
+
+func (w *wavesFlag) Set(s string) error {
+	*w = append(*w, s)
+	return nil
+}
+
+// parseWave parses one -wave flag value, e.g.
+// "sine:period=10m,mean=100,amp=30,phase=0".
+func parseWave(spec string) (wave, error) {
+	kind, rest, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid -wave %q, want kind:key=value,...", spec)
+	}
+
+	kv, err := parseKV(rest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -wave %q: %s", spec, err)
+	}
+
+	switch kind {
+	case "sine":
+		return sineWave{
+			period: kv.duration("period"),
+			mean:   kv.float("mean"),
+			amp:    kv.float("amp"),
+			phase:  kv.float("phase"),
+		}, kv.err
+	case "ramp":
+		return rampWave{
+			start:    kv.float("start"),
+			end:      kv.float("end"),
+			duration: kv.duration("duration"),
+		}, kv.err
+	case "square":
+		return squareWave{
+			period: kv.duration("period"),
+			low:    kv.float("low"),
+			high:   kv.float("high"),
+			duty:   kv.float("duty"),
+		}, kv.err
+	default:
+		return nil, fmt.Errorf("unknown -wave kind %q, want sine, ramp or square", kind)
+	}
+}
+
+// kvSet is a tiny key=value,key=value parser that accumulates the first
+// error it hits so callers can extract every field and check err once.
+type kvSet struct {
+	values map[string]string
+	err    error
+}
+
+func parseKV(s string) (*kvSet, error) {
+	kv := &kvSet{values: map[string]string{}}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected key=value, got %q", pair)
+		}
+		kv.values[k] = v
+	}
+	return kv, nil
+}
+
+func (kv *kvSet) float(key string) float64 {
+	v, err := strconv.ParseFloat(kv.values[key], 64)
+	if err != nil && kv.err == nil {
+		kv.err = fmt.Errorf("invalid %s: %s", key, err)
+	}
+	return v
+}
+
+func (kv *kvSet) duration(key string) time.Duration {
+	v, err := time.ParseDuration(kv.values[key])
+	if err != nil && kv.err == nil {
+		kv.err = fmt.Errorf("invalid %s: %s", key, err)
+	}
+	return v
+}
+
+// buildPacer turns the -wave flags, if any, into a CompoundPacer,
+// otherwise falls back to a single vegeta.SinePacer built from opts.
+// Shared by the attack and plot subcommands, the latter needing it to
+// overlay the theoretical rate curve on a recorded run.
+func buildPacer(opts paceOpts, waves []string) (vegeta.Pacer, error) {
+	if len(waves) > 0 {
+		components := make([]wave, len(waves))
+		for i, spec := range waves {
+			w, err := parseWave(spec)
+			if err != nil {
+				return nil, err
+			}
+			components[i] = w
+		}
+		return NewCompoundPacer(components...)
+	}
+
+	sine := vegeta.SinePacer{
+		Period:  opts.period,
+		Mean:    vegeta.Rate{Freq: opts.mean, Per: time.Second},
+		Amp:     vegeta.Rate{Freq: opts.amplitude, Per: time.Second},
+		StartAt: opts.startAt,
+	}
+	if invalid(sine) {
+		return nil, fmt.Errorf("sorry, your Sine pacer config is invalid: Mean must be positive, Amplitude must be smaller than Mean")
+	}
+
+	return sine, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func closeEnough(a, b, eps float64) bool {
+	return math.Abs(a-b) <= eps
+}
+
+func TestSineWaveRateAndIntegral(t *testing.T) {
+	w := sineWave{period: time.Minute, mean: 100, amp: 30, phase: 0}
+
+	if got := w.Rate(0); !closeEnough(got, 100, 1e-9) {
+		t.Errorf("Rate(0) = %v, want 100 (sin(0) == 0)", got)
+	}
+	if got := w.Rate(15 * time.Second); !closeEnough(got, 130, 1e-6) {
+		t.Errorf("Rate(period/4) = %v, want 130 (sin peaks at amp)", got)
+	}
+
+	// The integral of a full period should equal mean * period, since
+	// the sine component integrates to zero over a whole cycle.
+	got := w.integral(time.Minute)
+	want := 100.0 * 60.0
+	if !closeEnough(got, want, 1e-6) {
+		t.Errorf("integral(period) = %v, want %v", got, want)
+	}
+}
+
+func TestRampWaveRateAndIntegral(t *testing.T) {
+	r := rampWave{start: 10, end: 110, duration: 10 * time.Second}
+
+	if got := r.Rate(0); got != 10 {
+		t.Errorf("Rate(0) = %v, want 10", got)
+	}
+	if got := r.Rate(5 * time.Second); got != 60 {
+		t.Errorf("Rate(5s) = %v, want 60", got)
+	}
+	if got := r.Rate(20 * time.Second); got != 110 {
+		t.Errorf("Rate(20s) = %v, want 110 once past duration", got)
+	}
+
+	// Integral over the ramp is the area of the trapezoid; beyond
+	// duration it should keep accumulating at the held end rate.
+	if got, want := r.integral(10*time.Second), 600.0; !closeEnough(got, want, 1e-6) {
+		t.Errorf("integral(duration) = %v, want %v", got, want)
+	}
+	if got, want := r.integral(20*time.Second), 600.0+110*10; !closeEnough(got, want, 1e-6) {
+		t.Errorf("integral(2*duration) = %v, want %v", got, want)
+	}
+}
+
+func TestSquareWaveRateAndIntegral(t *testing.T) {
+	s := squareWave{period: 10 * time.Second, low: 50, high: 150, duty: 0.3}
+
+	if got := s.Rate(0); got != 150 {
+		t.Errorf("Rate(0) = %v, want 150 (inside duty)", got)
+	}
+	if got := s.Rate(5 * time.Second); got != 50 {
+		t.Errorf("Rate(5s) = %v, want 50 (past duty)", got)
+	}
+
+	// One full period contributes duty*period at high plus the rest at low.
+	want := 0.3*10*150 + 0.7*10*50
+	if got := s.integral(10 * time.Second); !closeEnough(got, want, 1e-6) {
+		t.Errorf("integral(period) = %v, want %v", got, want)
+	}
+	// Two periods should simply double it.
+	if got := s.integral(20 * time.Second); !closeEnough(got, 2*want, 1e-6) {
+		t.Errorf("integral(2*period) = %v, want %v", got, 2*want)
+	}
+}
+
+func TestWaveScaledDividesRate(t *testing.T) {
+	waves := []wave{
+		sineWave{period: time.Minute, mean: 100, amp: 20},
+		rampWave{start: 10, end: 50, duration: time.Minute},
+		squareWave{period: time.Minute, low: 10, high: 90, duty: 0.5},
+	}
+	for _, w := range waves {
+		scaled := w.scaled(2)
+		if got, want := scaled.Rate(0), w.Rate(0)/2; !closeEnough(got, want, 1e-9) {
+			t.Errorf("%T.scaled(2).Rate(0) = %v, want %v", w, got, want)
+		}
+	}
+}
+
+func TestCompoundPacerRateSumsComponents(t *testing.T) {
+	cp, err := NewCompoundPacer(
+		sineWave{period: time.Minute, mean: 100, amp: 20},
+		rampWave{start: 0, end: 0, duration: time.Minute},
+	)
+	if err != nil {
+		t.Fatalf("NewCompoundPacer: %s", err)
+	}
+
+	want := 100.0 // sine at t=0 plus a flat zero ramp
+	if got := cp.Rate(0); !closeEnough(got, want, 1e-9) {
+		t.Errorf("Rate(0) = %v, want %v", got, want)
+	}
+}
+
+func TestCompoundPacerValidateRejectsNonPositiveRate(t *testing.T) {
+	_, err := NewCompoundPacer(sineWave{period: time.Minute, mean: 10, amp: 20})
+	if err == nil {
+		t.Fatal("expected an error when amplitude exceeds mean, got nil")
+	}
+}
+
+func TestCompoundPacerInvertMatchesIntegral(t *testing.T) {
+	cp, err := NewCompoundPacer(sineWave{period: time.Minute, mean: 100, amp: 10})
+	if err != nil {
+		t.Fatalf("NewCompoundPacer: %s", err)
+	}
+
+	for _, target := range []float64{1, 100, 1000, 10000} {
+		at := cp.invert(target)
+		got := cp.integral(at)
+		if !closeEnough(got, target, 1e-3) {
+			t.Errorf("integral(invert(%v)) = %v, want %v", target, got, target)
+		}
+	}
+}
+
+func TestCompoundPacerPaceAdvancesWithHits(t *testing.T) {
+	cp, err := NewCompoundPacer(sineWave{period: time.Minute, mean: 100, amp: 10})
+	if err != nil {
+		t.Fatalf("NewCompoundPacer: %s", err)
+	}
+
+	wait, stop := cp.Pace(0, 0)
+	if stop {
+		t.Fatal("Pace(0, 0) should never ask the attacker to stop")
+	}
+	if wait != 0 {
+		t.Errorf("Pace(0, 0) wait = %v, want 0 for the very first hit", wait)
+	}
+
+	// Asking to pace far more hits than could plausibly have occurred by
+	// elapsed=0 should yield a wait, not an immediate hit.
+	wait, _ = cp.Pace(0, 1000)
+	if wait <= 0 {
+		t.Errorf("Pace(0, 1000) wait = %v, want a positive wait", wait)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// formatFromPath guesses a results encoding from a file's extension,
+// defaulting to vegeta's own gob encoding when there's no better hint -
+// the same default "sine attack" writes with vegeta.NewEncoder.
+func formatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".csv":
+		return "csv"
+	default:
+		return "gob"
+	}
+}
+
+func decoderFor(format string, r io.Reader) (vegeta.Decoder, error) {
+	switch format {
+	case "gob", "":
+		return vegeta.NewDecoder(r), nil
+	case "json":
+		return vegeta.NewJSONDecoder(r), nil
+	case "csv":
+		return vegeta.NewCSVDecoder(r), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, want \"gob\", \"json\" or \"csv\"", format)
+	}
+}
+
+func encoderFor(format string, w io.Writer) (vegeta.Encoder, error) {
+	switch format {
+	case "gob", "":
+		return vegeta.NewEncoder(w), nil
+	case "json":
+		return vegeta.NewJSONEncoder(w), nil
+	case "csv":
+		return vegeta.NewCSVEncoder(w), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, want \"gob\", \"json\" or \"csv\"", format)
+	}
+}
+
+// readResults loads every result from each of paths, decoding them as
+// format or, when format is "auto" or empty, guessing each file's format
+// from its extension, and returns them merged chronologically
+// the way vegeta's own multi-input "report"/"plot" commands do.
+func readResults(paths []string, format string) ([]*vegeta.Result, error) {
+	var all []*vegeta.Result
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open %q: %s", path, err)
+		}
+
+		fileFormat := format
+		if fileFormat == "auto" || fileFormat == "" {
+			fileFormat = formatFromPath(path)
+		}
+
+		dec, err := decoderFor(fileFormat, f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		for {
+			var res vegeta.Result
+			if err := dec.Decode(&res); err != nil {
+				if err == io.EOF {
+					break
+				}
+				f.Close()
+				return nil, fmt.Errorf("couldn't decode %q: %s", path, err)
+			}
+			all = append(all, &res)
+		}
+
+		f.Close()
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Timestamp.Before(all[j].Timestamp) })
+
+	return all, nil
+}
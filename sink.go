@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Bucket is one downsampled window of a Range query.
+type Bucket struct {
+	Start        time.Time     `json:"start"`
+	Count        int           `json:"count"`
+	Mean         time.Duration `json:"mean"`
+	P50          time.Duration `json:"p50"`
+	P95          time.Duration `json:"p95"`
+	P99          time.Duration `json:"p99"`
+	SuccessRatio float64       `json:"success_ratio"`
+}
+
+// Sink receives every vegeta.Result as it's produced during an attack and
+// makes it queryable by time range without keeping the whole run in
+// memory, which matters because sine tests routinely run for hours.
+type Sink interface {
+	Add(res *vegeta.Result)
+	Close() error
+	Range(metric string, from, to time.Time, step time.Duration) ([]Bucket, error)
+}
+
+// segmentSpan is how much wall-clock time each on-disk segment covers
+// before it's rotated. Together with maxSegments it bounds how much of the
+// run is kept on disk.
+const segmentSpan = 5 * time.Minute
+
+// tombstoneGrace is how long a pruned segment stays on disk under its
+// .tombstone name before it's actually removed. A Range query that
+// captured the segment list just before a concurrent prune still has the
+// pre-rename path, but the grace window gives any query that's slower
+// than that a chance to fall back to the tombstoned copy in scanSegment
+// instead of silently losing data.
+const tombstoneGrace = 30 * time.Second
+
+// tombstone is a pruned segment that's been renamed but not yet removed.
+type tombstone struct {
+	path string // the .tombstone path, not the original
+	at   time.Time
+}
+
+// fileSink is an append-only, segmented time-series sink. Each segment is
+// a file of newline-delimited JSON-encoded vegeta.Results named by its
+// start time. Segments older than maxSegments are tombstoned (renamed
+// with a .tombstone suffix) and kept for tombstoneGrace before being
+// removed, so disk usage stays bounded regardless of how long the attack
+// runs while still giving in-flight Range queries a window to read them.
+type fileSink struct {
+	dir         string
+	maxSegments int
+
+	mu         sync.Mutex
+	cur        *os.File
+	curStart   time.Time
+	segments   []string    // oldest first, absolute paths, excludes cur
+	tombstones []tombstone // pruned segments still within their grace period
+}
+
+// NewFileSink opens (creating if needed) a segmented result store rooted
+// at dir, keeping at most maxSegments of segmentSpan each on disk.
+func NewFileSink(dir string, maxSegments int) (*fileSink, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("couldn't create storage dir %q: %s", dir, err)
+	}
+
+	s := &fileSink{dir: dir, maxSegments: maxSegments}
+	if err := s.rotate(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *fileSink) segmentPath(start time.Time) string {
+	return filepath.Join(s.dir, start.UTC().Format("20060102T150405.000000000")+".jsonl")
+}
+
+// rotate must be called with s.mu held or before any Add.
+func (s *fileSink) rotate() error {
+	if s.cur != nil {
+		if err := s.cur.Close(); err != nil {
+			return err
+		}
+		s.segments = append(s.segments, s.cur.Name())
+	}
+
+	s.curStart = time.Now()
+	f, err := os.Create(s.segmentPath(s.curStart))
+	if err != nil {
+		return fmt.Errorf("couldn't create segment: %s", err)
+	}
+	s.cur = f
+
+	return s.prune()
+}
+
+// prune tombstones segments beyond maxSegments, oldest first, then reaps
+// any previously tombstoned segment whose grace period has elapsed.
+func (s *fileSink) prune() error {
+	for len(s.segments) > s.maxSegments {
+		path := s.segments[0]
+		s.segments = s.segments[1:]
+
+		tomb := path + ".tombstone"
+		if err := os.Rename(path, tomb); err != nil {
+			return fmt.Errorf("couldn't tombstone segment %q: %s", path, err)
+		}
+		s.tombstones = append(s.tombstones, tombstone{path: tomb, at: time.Now()})
+	}
+	return s.reapTombstones()
+}
+
+// reapTombstones removes tombstoned segments whose grace period has
+// elapsed. Must be called with s.mu held.
+func (s *fileSink) reapTombstones() error {
+	live := s.tombstones[:0]
+	for _, tomb := range s.tombstones {
+		if time.Since(tomb.at) < tombstoneGrace {
+			live = append(live, tomb)
+			continue
+		}
+		if err := os.Remove(tomb.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("couldn't remove tombstoned segment %q: %s", tomb.path, err)
+		}
+	}
+	s.tombstones = live
+	return nil
+}
+
+func (s *fileSink) Add(res *vegeta.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.curStart) >= segmentSpan {
+		if err := s.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "sink: %s\n", err)
+			return
+		}
+	}
+
+	enc := json.NewEncoder(s.cur)
+	if err := enc.Encode(res); err != nil {
+		fmt.Fprintf(os.Stderr, "sink: couldn't write result: %s\n", err)
+	}
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cur == nil {
+		return nil
+	}
+	return s.cur.Close()
+}
+
+// Range returns one Bucket per step-sized window between from and to,
+// computed by scanning every retained, tombstoned and current segment and
+// keeping only the results whose timestamps fall within that span.
+func (s *fileSink) Range(metric string, from, to time.Time, step time.Duration) ([]Bucket, error) {
+	if metric != "latency" {
+		return nil, fmt.Errorf("unsupported metric %q, only \"latency\" is available", metric)
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("step must be positive")
+	}
+
+	s.mu.Lock()
+	paths := append([]string{}, s.segments...)
+	for _, tomb := range s.tombstones {
+		paths = append(paths, tomb.path)
+	}
+	paths = append(paths, s.cur.Name())
+	s.mu.Unlock()
+
+	nbuckets := int(to.Sub(from)/step) + 1
+	if nbuckets < 1 {
+		return nil, nil
+	}
+	latencies := make([][]time.Duration, nbuckets)
+	successes := make([]int, nbuckets)
+	counts := make([]int, nbuckets)
+
+	for _, path := range paths {
+		if err := scanSegment(path, func(res *vegeta.Result) {
+			if res.Timestamp.Before(from) || res.Timestamp.After(to) {
+				return
+			}
+			i := int(res.Timestamp.Sub(from) / step)
+			if i < 0 || i >= nbuckets {
+				return
+			}
+			latencies[i] = append(latencies[i], res.Latency)
+			counts[i]++
+			if res.Error == "" {
+				successes[i]++
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	buckets := make([]Bucket, nbuckets)
+	for i := range buckets {
+		b := Bucket{Start: from.Add(time.Duration(i) * step), Count: counts[i]}
+		if counts[i] > 0 {
+			b.SuccessRatio = float64(successes[i]) / float64(counts[i])
+			b.Mean, b.P50, b.P95, b.P99 = summarize(latencies[i])
+		}
+		buckets[i] = b
+	}
+
+	return buckets, nil
+}
+
+// scanSegment decodes every result in the segment at path. If path has
+// been tombstoned since it was captured, its .tombstone copy is read
+// instead; an already-removed segment is skipped instead of failing the
+// query.
+func scanSegment(path string, fn func(*vegeta.Result)) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		f, err = os.Open(path + ".tombstone")
+	}
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("couldn't open segment %q: %s", path, err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var res vegeta.Result
+		if err := dec.Decode(&res); err != nil {
+			break
+		}
+		fn(&res)
+	}
+
+	return nil
+}
+
+func summarize(latencies []time.Duration) (mean, p50, p95, p99 time.Duration) {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+	mean = sum / time.Duration(len(latencies))
+
+	pct := func(p float64) time.Duration {
+		i := int(p * float64(len(latencies)))
+		if i >= len(latencies) {
+			i = len(latencies) - 1
+		}
+		return latencies[i]
+	}
+
+	return mean, pct(0.50), pct(0.95), pct(0.99)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestFileSinkAddAndRange(t *testing.T) {
+	s, err := NewFileSink(t.TempDir(), 288)
+	if err != nil {
+		t.Fatalf("NewFileSink: %s", err)
+	}
+	defer s.Close()
+
+	base := time.Now().Truncate(time.Second)
+	s.Add(&vegeta.Result{Timestamp: base, Latency: 10 * time.Millisecond})
+	s.Add(&vegeta.Result{Timestamp: base.Add(time.Second), Latency: 20 * time.Millisecond})
+	s.Add(&vegeta.Result{Timestamp: base.Add(2 * time.Second), Latency: 30 * time.Millisecond, Error: "boom"})
+
+	buckets, err := s.Range("latency", base, base.Add(2*time.Second), time.Second)
+	if err != nil {
+		t.Fatalf("Range: %s", err)
+	}
+	if len(buckets) != 3 {
+		t.Fatalf("got %d buckets, want 3", len(buckets))
+	}
+	if buckets[0].Count != 1 || buckets[0].Mean != 10*time.Millisecond {
+		t.Errorf("bucket 0 = %+v, want count=1 mean=10ms", buckets[0])
+	}
+	if buckets[2].SuccessRatio != 0 {
+		t.Errorf("bucket 2 success ratio = %v, want 0 (its only result errored)", buckets[2].SuccessRatio)
+	}
+
+	if _, err := s.Range("p99", base, base, time.Second); err == nil {
+		t.Error("expected an error for an unsupported metric")
+	}
+}
+
+func TestFileSinkPruneBoundsSegments(t *testing.T) {
+	s, err := NewFileSink(t.TempDir(), 2)
+	if err != nil {
+		t.Fatalf("NewFileSink: %s", err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := s.rotate(); err != nil {
+			t.Fatalf("rotate: %s", err)
+		}
+	}
+
+	if len(s.segments) != 2 {
+		t.Errorf("got %d retained segments, want maxSegments (2)", len(s.segments))
+	}
+	if len(s.tombstones) != 3 {
+		t.Errorf("got %d tombstoned segments, want 3 (pruned but within grace)", len(s.tombstones))
+	}
+}
+
+func TestFileSinkReapTombstonesWaitsForGrace(t *testing.T) {
+	s, err := NewFileSink(t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("NewFileSink: %s", err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := s.rotate(); err != nil {
+			t.Fatalf("rotate: %s", err)
+		}
+	}
+	if len(s.tombstones) != 1 {
+		t.Fatalf("got %d tombstones, want 1", len(s.tombstones))
+	}
+	tomb := s.tombstones[0]
+
+	if err := s.reapTombstones(); err != nil {
+		t.Fatalf("reapTombstones: %s", err)
+	}
+	if len(s.tombstones) != 1 {
+		t.Fatalf("tombstone was reaped before its grace period elapsed")
+	}
+
+	s.tombstones[0].at = tomb.at.Add(-tombstoneGrace)
+	if err := s.reapTombstones(); err != nil {
+		t.Fatalf("reapTombstones: %s", err)
+	}
+	if len(s.tombstones) != 0 {
+		t.Errorf("got %d tombstones after grace elapsed, want 0", len(s.tombstones))
+	}
+}
+
+func TestSummarizePercentiles(t *testing.T) {
+	latencies := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		30 * time.Millisecond,
+		40 * time.Millisecond,
+		100 * time.Millisecond,
+	}
+	mean, p50, _, _ := summarize(latencies)
+
+	if want := 40 * time.Millisecond; mean != want {
+		t.Errorf("mean = %v, want %v", mean, want)
+	}
+	if p50 != 30*time.Millisecond {
+		t.Errorf("p50 = %v, want 30ms", p50)
+	}
+}
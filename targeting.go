@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// newTargeter builds a vegeta.Targeter for the requested format, reading
+// from src. "auto" sniffs the first non-whitespace byte of src: a '{'
+// means JSON targets, anything else is treated as vegeta's HTTP text
+// format. body is used as the default body for targets that don't
+// specify their own, matching the upstream vegeta CLI's -body flag.
+func newTargeter(format string, src io.Reader, body []byte) (vegeta.Targeter, error) {
+	switch format {
+	case "json":
+		return vegeta.NewJSONTargeter(src, body, http.Header{}), nil
+	case "http":
+		return vegeta.NewHTTPTargeter(src, body, http.Header{}), nil
+	case "auto":
+		br := bufio.NewReader(src)
+		first, err := sniffFirstByte(br)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't sniff targets format: %s", err)
+		}
+		if first == '{' {
+			return vegeta.NewJSONTargeter(br, body, http.Header{}), nil
+		}
+		return vegeta.NewHTTPTargeter(br, body, http.Header{}), nil
+	default:
+		return nil, fmt.Errorf("unknown -format %q, want \"http\", \"json\" or \"auto\"", format)
+	}
+}
+
+// sniffFirstByte returns the first non-whitespace byte read from br,
+// consuming only the whitespace before it, so the caller can still read
+// the stream from that byte on afterwards.
+func sniffFirstByte(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\n' || b[0] == '\r' {
+			if _, err := br.Discard(1); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		return b[0], nil
+	}
+}
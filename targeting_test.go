@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestNewTargeterAutoSniffsJSON(t *testing.T) {
+	src := strings.NewReader(`{"method":"GET","url":"http://example.com"}` + "\n")
+	targeter, err := newTargeter("auto", src, nil)
+	if err != nil {
+		t.Fatalf("newTargeter: %s", err)
+	}
+	if targeter == nil {
+		t.Fatal("expected a non-nil targeter")
+	}
+}
+
+func TestNewTargeterAutoSniffsHTTP(t *testing.T) {
+	src := strings.NewReader("GET http://example.com\n")
+	targeter, err := newTargeter("auto", src, nil)
+	if err != nil {
+		t.Fatalf("newTargeter: %s", err)
+	}
+	if targeter == nil {
+		t.Fatal("expected a non-nil targeter")
+	}
+}
+
+func TestNewTargeterUnknownFormat(t *testing.T) {
+	if _, err := newTargeter("yaml", strings.NewReader(""), nil); err == nil {
+		t.Fatal("expected an error for an unknown -format")
+	}
+}
+
+func TestSniffFirstByteSkipsWhitespace(t *testing.T) {
+	br := bufio.NewReader(strings.NewReader("  \n\t{\"a\":1}"))
+	b, err := sniffFirstByte(br)
+	if err != nil {
+		t.Fatalf("sniffFirstByte: %s", err)
+	}
+	if b != '{' {
+		t.Errorf("got %q, want '{'", b)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// loadVars builds the variable map used to expand target templates. It
+// starts from the (optional) -vars JSON file and then applies any
+// -var key=value flags on top, so repeated flags can override individual
+// entries from the file without having to edit it.
+func loadVars(path string, inline []string) (map[string]string, error) {
+	vars := map[string]string{}
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open vars file %q: %s", path, err)
+		}
+		defer f.Close()
+
+		if err := json.NewDecoder(f).Decode(&vars); err != nil {
+			return nil, fmt.Errorf("couldn't parse vars file %q as JSON: %s", path, err)
+		}
+	}
+
+	for _, kv := range inline {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid -var %q, want key=value", kv)
+		}
+		vars[parts[0]] = parts[1]
+	}
+
+	return vars, nil
+}
+
+// expand runs s through text/template using vars as the dot context, e.g.
+// "https://api.example.com/users/{{.id}}" with vars["id"] = "42".
+func expand(s string, vars map[string]string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	tmpl, err := template.New("target").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// templatingTargeter wraps another Targeter and expands Go text/template
+// placeholders in the URL, body and header values of every target it
+// produces, using vars as the substitution source. Expansion happens on
+// every call, so -vars values can change between runs of a replayed
+// targets file without regenerating it.
+func templatingTargeter(next vegeta.Targeter, vars map[string]string) vegeta.Targeter {
+	if len(vars) == 0 {
+		return next
+	}
+
+	return func(tgt *vegeta.Target) error {
+		if err := next(tgt); err != nil {
+			return err
+		}
+
+		url, err := expand(tgt.URL, vars)
+		if err != nil {
+			return fmt.Errorf("couldn't expand URL template: %s", err)
+		}
+		tgt.URL = url
+
+		if len(tgt.Body) > 0 {
+			body, err := expand(string(tgt.Body), vars)
+			if err != nil {
+				return fmt.Errorf("couldn't expand body template: %s", err)
+			}
+			tgt.Body = []byte(body)
+		}
+
+		// Every round-robined Target from vegeta.NewStaticTargeter shares
+		// the same underlying Header by reference, and Targeter is called
+		// concurrently from every attack worker, so expand into a fresh
+		// Header here rather than mutating tgt.Header in place.
+		header := make(http.Header, len(tgt.Header))
+		for key, values := range tgt.Header {
+			expanded := make([]string, len(values))
+			for i, v := range values {
+				ev, err := expand(v, vars)
+				if err != nil {
+					return fmt.Errorf("couldn't expand %q header template: %s", key, err)
+				}
+				expanded[i] = ev
+			}
+			header[key] = expanded
+		}
+		tgt.Header = header
+
+		return nil
+	}
+}
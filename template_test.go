@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestLoadVarsFileAndInlineOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.json")
+	if err := os.WriteFile(path, []byte(`{"host":"a.example.com","id":"1"}`), 0o644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	vars, err := loadVars(path, []string{"id=2"})
+	if err != nil {
+		t.Fatalf("loadVars: %s", err)
+	}
+	if vars["host"] != "a.example.com" {
+		t.Errorf("host = %q, want a.example.com (from file)", vars["host"])
+	}
+	if vars["id"] != "2" {
+		t.Errorf("id = %q, want 2 (inline flag overrides file)", vars["id"])
+	}
+}
+
+func TestLoadVarsInvalidInline(t *testing.T) {
+	if _, err := loadVars("", []string{"noequalssign"}); err == nil {
+		t.Fatal("expected an error for a -var without key=value")
+	}
+}
+
+func TestExpandLeavesPlainStringsAlone(t *testing.T) {
+	got, err := expand("http://example.com/users", map[string]string{"id": "1"})
+	if err != nil {
+		t.Fatalf("expand: %s", err)
+	}
+	if got != "http://example.com/users" {
+		t.Errorf("got %q, want the input unchanged", got)
+	}
+}
+
+func TestExpandSubstitutesVars(t *testing.T) {
+	got, err := expand("http://example.com/users/{{.id}}", map[string]string{"id": "42"})
+	if err != nil {
+		t.Fatalf("expand: %s", err)
+	}
+	if got != "http://example.com/users/42" {
+		t.Errorf("got %q, want .../42", got)
+	}
+}
+
+func TestTemplatingTargeterExpandsURLAndHeaders(t *testing.T) {
+	next := func(tgt *vegeta.Target) error {
+		tgt.URL = "http://example.com/users/{{.id}}"
+		tgt.Header = map[string][]string{"X-User": {"{{.id}}"}}
+		return nil
+	}
+
+	targeter := templatingTargeter(next, map[string]string{"id": "7"})
+
+	var tgt vegeta.Target
+	if err := targeter(&tgt); err != nil {
+		t.Fatalf("targeter: %s", err)
+	}
+	if tgt.URL != "http://example.com/users/7" {
+		t.Errorf("URL = %q, want .../7", tgt.URL)
+	}
+	if tgt.Header.Get("X-User") != "7" {
+		t.Errorf("X-User header = %q, want 7", tgt.Header.Get("X-User"))
+	}
+}
+
+// TestTemplatingTargeterConcurrentCallsDontRace exercises the case of a
+// vegeta.NewStaticTargeter-style targeter that round-robins Target values
+// sharing the same underlying Header by reference: templatingTargeter must
+// expand into a fresh Header rather than mutating the shared one in place,
+// since Targeter is called concurrently from every attack worker. Run with
+// -race to catch a regression.
+func TestTemplatingTargeterConcurrentCallsDontRace(t *testing.T) {
+	shared := http.Header{"X-User": {"{{.id}}"}}
+	next := func(tgt *vegeta.Target) error {
+		tgt.URL = "http://example.com/users/{{.id}}"
+		tgt.Header = shared
+		return nil
+	}
+
+	targeter := templatingTargeter(next, map[string]string{"id": "7"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var tgt vegeta.Target
+			if err := targeter(&tgt); err != nil {
+				t.Errorf("targeter: %s", err)
+				return
+			}
+			if tgt.Header.Get("X-User") != "7" {
+				t.Errorf("X-User header = %q, want 7", tgt.Header.Get("X-User"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if shared.Get("X-User") != "{{.id}}" {
+		t.Errorf("shared Header was mutated in place, got %q", shared.Get("X-User"))
+	}
+}
@@ -1,14 +1,10 @@
 package main
 
 import (
-	"flag"
 	"fmt"
-	"log"
-	"net/http"
 	"os"
+	"strings"
 	"time"
-
-	vegeta "github.com/tsenart/vegeta/v12/lib"
 )
 
 // Rounding support lifted from Vegeta reporters since it is private.
@@ -42,101 +38,48 @@ type paceOpts struct {
 	keepalive bool
 }
 
-// hitsPerNs returns the attack rate this ConstantPacer represents, in
-// fractional hits per nanosecond.
-func hitsPerNs(cp vegeta.ConstantPacer) float64 {
-	return float64(cp.Freq) / float64(cp.Per)
-}
+// varsFlag collects repeated -var key=value flags in the order they were
+// given, so they can be layered on top of a -vars file.
+type varsFlag []string
 
-func invalid(sp vegeta.SinePacer) bool {
-	return sp.Period <= 0 || hitsPerNs(sp.Mean) <= 0 || hitsPerNs(sp.Amp) >= hitsPerNs(sp.Mean)
-}
-
-func main() {
-	// Parse the commandline options
-	opts := paceOpts{}
-	flag.DurationVar(&opts.period, "period", 10*time.Minute, "Period of the sine wave")
-	flag.IntVar(&opts.mean, "mean", 2, "The Mean req/1s of the sine wave")
-	flag.IntVar(&opts.amplitude, "amplitude", 1, "The Amplitude in req/1s of the sine wave")
-	flag.Float64Var(&opts.startAt, "startAt", 0, "The phase at which to start the sine wave, in radians")
-	flag.DurationVar(&opts.duration, "duration", 0, "Duration of the test in seconds")
-	flag.DurationVar(&opts.timeout, "timeout", vegeta.DefaultTimeout, "Requests timeout")
-	flag.BoolVar(&opts.keepalive, "keepalive", true, "Use persistent connections")
-	flag.Parse()
+func (v *varsFlag) String() string { return strings.Join(*v, ",") }
 
-	fmt.Fprintf(os.Stderr, "Options: %#v\n", opts)
-
-	// These values are well-described at
-	// https://github.com/tsenart/vegeta/blob/d73edf2bc2663d83848da2a97a8401a7ed1440bc/lib/pacer.go#L101-L132
-	var pacer vegeta.SinePacer
-	pacer = vegeta.SinePacer{
-		Period: opts.period,
-		// The mid-point of the sine wave in freq-per-Duration,
-		// MUST BE > 0
-		Mean: vegeta.Rate{
-			Freq: opts.mean,
-			Per:  time.Second},
-		// The amplitude of the sine wave in freq-per-Duration,
-		// MUST NOT BE EQUAL TO OR LARGER THAN MEAN
-		Amp: vegeta.Rate{
-			Freq: opts.amplitude,
-			Per:  time.Second},
-		StartAt: opts.startAt,
-	}
-
-	fmt.Fprintf(os.Stderr, "Using pacer: %v\n", pacer)
-	if invalid(pacer) {
-		msg := fmt.Errorf("Sorry, your Sine pacer config is invalid. Mean must be positive, Amplitude must not be larger than Mean.")
-		log.Fatal(msg)
-	}
+func (v *varsFlag) Set(s string) error {
+	*v = append(*v, s)
+	return nil
+}
 
-	var duration_text string
-	if opts.duration == 0 {
-		duration_text = "infinity"
-	} else {
-		duration_text = fmt.Sprintf("%v", round(opts.duration))
-	}
+const usage = `Usage: sine <command> [flags]
 
-	targeter := vegeta.NewJSONTargeter(os.Stdin, []byte{}, http.Header{})
+Commands:
+  attack   Run a sine/compound-wave load test against a set of targets
+  report   Build a report from one or more recorded attack result files
+  plot     Render a recorded attack as an HTML latency-over-time chart
+  encode   Convert recorded attack results between gob, json and csv
 
-	// Let's check if there's anything on os.Stdin - otherwise it'll
-	// just hang, waiting for an EOF.
-	stat, _ := os.Stdin.Stat()
-	if (stat.Mode() & os.ModeCharDevice) != 0 {
-		msg := fmt.Errorf("Please provide targets on /dev/stdin, in JSON format.")
-		log.Fatal(msg)
-	}
+Run "sine <command> -h" for flags specific to that command.
+`
 
-	// Eagerly read all targets from os.Stdin.
-	targets, err := vegeta.ReadAllTargets(targeter)
-	if err != nil {
-		msg := fmt.Errorf("Couldn't figure out JSON targets from /dev/stdin: %s", err)
-		log.Fatal(msg)
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
-	targeter = vegeta.NewStaticTargeter(targets...)
-
-	attacker := vegeta.NewAttacker(
-		vegeta.KeepAlive(opts.keepalive),
-		vegeta.Timeout(opts.timeout),
-	)
-	enc := vegeta.NewEncoder(os.Stdout)
-	var metrics vegeta.Metrics
-	fmt.Fprintf(os.Stderr, "🚀  Starting sine load test for %s\n", duration_text)
-	startedAt := time.Now()
 
-	for res := range attacker.Attack(targeter, pacer, opts.duration, "sine load") {
-		metrics.Add(res)
-		if err = enc.Encode(res); err != nil {
-			msg := fmt.Errorf("error during attack: %s", err)
-			log.Fatal(msg)
-		}
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "attack":
+		cmdAttack(args)
+	case "report":
+		cmdReport(args)
+	case "plot":
+		cmdPlot(args)
+	case "encode":
+		cmdEncode(args)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stderr, usage)
+	default:
+		fmt.Fprintf(os.Stderr, "sine: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
 	}
-
-	metrics.Close()
-
-	reporter := vegeta.NewTextReporter(&metrics)
-	reporter.Report(os.Stdout)
-
-	attackDuration := time.Since(startedAt)
-	fmt.Fprintf(os.Stderr, "✨  Variable load test completed in %v\n", round(attackDuration))
 }